@@ -0,0 +1,888 @@
+package rbtree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// mapNode is a node of a Map's red-black tree, parameterized on key and
+// value so that Map[K, V] never boxes either of them into an interface.
+type mapNode[K any, V any] struct {
+	key    K
+	value  V
+	color  color
+	size   int // number of nodes in the subtree rooted at n, including n
+	parent *mapNode[K, V]
+	tree   *Map[K, V]
+	left   *mapNode[K, V]
+	right  *mapNode[K, V]
+}
+
+// sizeOf returns n.size, treating a nil node as an empty subtree.
+func (n *mapNode[K, V]) sizeOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *mapNode[K, V]) check() bool {
+	cmp := n.tree.cmp
+	if n.left != nil {
+		if cmp(n.left.key, n.key) >= 0 {
+			return false
+		}
+		if n.left.parent != n {
+			return false
+		}
+		if !n.left.check() {
+			return false
+		}
+	}
+	if n.right != nil {
+		if cmp(n.key, n.right.key) >= 0 {
+			return false
+		}
+		if n.right.parent != n {
+			return false
+		}
+		if !n.right.check() {
+			return false
+		}
+	}
+	return n.size == 1+n.left.sizeOf()+n.right.sizeOf()
+}
+
+func (n *mapNode[K, V]) depth() int {
+	var ld, rd int
+	if n.left != nil {
+		ld = n.left.depth()
+	}
+	if n.right != nil {
+		rd = n.right.depth()
+	}
+	if ld > rd {
+		return ld + 1
+	} else {
+		return rd + 1
+	}
+}
+
+func (n *mapNode[K, V]) keys() []K {
+	var ks []K
+	if n.left != nil {
+		ks = n.left.keys()
+	}
+	ks = append(ks, n.key)
+	if n.right != nil {
+		ks = append(ks, n.right.keys()...)
+	}
+	return ks
+}
+
+// foreign reports whether n was built for a different Map than t. With
+// rebuilds every node on the path from the root down to the inserted key
+// (stamping each with the new Map as its tree), but reuses every
+// untouched subtree by pointer, leaving those nodes stamped with whatever
+// Map they were first built for — and, transitively, everything below
+// them, since an unrebuilt subtree is never partially rebuilt. So a plain
+// tree.tree comparison is enough to tell apart a node (and its parent
+// pointer, which by the same argument is trustworthy whenever the node
+// itself is native) that's safe to mutate in place from one still shared
+// with, and relied on by, another Map.
+func (t *Map[K, V]) foreign(n *mapNode[K, V]) bool {
+	return n.tree != t
+}
+
+func (n *mapNode[K, V]) find(key K) (*mapNode[K, V], direction) {
+	c := n.tree.cmp(key, n.key)
+	switch {
+	case c == 0:
+		return n, exact
+	case c < 0:
+		if n.left == nil {
+			return n, left
+		} else {
+			return n.left.find(key)
+		}
+	case c > 0:
+		if n.right == nil {
+			return n, right
+		} else {
+			return n.right.find(key)
+		}
+	}
+	panic("bad red-black node")
+}
+
+// path returns the nodes from the root down to key, ending either at the
+// matching node or at the node where a search for key would stop. Unlike
+// a parent-pointer walk, this is derived purely from the left/right links
+// seen on the way down, so it is correct even for a node shared from
+// another Map via With, whose own parent pointer is stale.
+func (t *Map[K, V]) path(key K) []*mapNode[K, V] {
+	var path []*mapNode[K, V]
+	n := t.root
+	for {
+		path = append(path, n)
+		c := t.cmp(key, n.key)
+		switch {
+		case c == 0:
+			return path
+		case c < 0:
+			if n.left == nil {
+				return path
+			}
+			n = n.left
+		default:
+			if n.right == nil {
+				return path
+			}
+			n = n.right
+		}
+	}
+}
+
+// successorOf returns the in-order successor of n. When n's parent chain
+// is trustworthy (the common case: n was never reached through a With
+// result) it walks up via n.parent, which is O(1) amortized across a full
+// traversal. Only when that chain is foreign — n was shared into t from
+// another Map's With call — does it fall back to recomputing the needed
+// ancestry from the root, since a foreign parent pointer may lead
+// anywhere, including nowhere near t.root.
+func (t *Map[K, V]) successorOf(n *mapNode[K, V]) (*mapNode[K, V], bool) {
+	if n.right != nil {
+		m := n.right
+		for m.left != nil {
+			m = m.left
+		}
+		return m, true
+	}
+	if !t.foreign(n) {
+		cur := n
+		for cur.parent != nil {
+			if cur.parent.left == cur {
+				return cur.parent, true
+			}
+			cur = cur.parent
+		}
+		return nil, false
+	}
+	path := t.path(n.key)
+	for i := len(path) - 2; i >= 0; i-- {
+		if path[i].left == path[i+1] {
+			return path[i], true
+		}
+	}
+	return nil, false
+}
+
+// predecessorOf returns the in-order predecessor of n. See successorOf
+// for why it prefers the O(1) parent-pointer walk and only falls back to
+// a root re-descent for a node with a foreign parent chain.
+func (t *Map[K, V]) predecessorOf(n *mapNode[K, V]) (*mapNode[K, V], bool) {
+	if n.left != nil {
+		m := n.left
+		for m.right != nil {
+			m = m.right
+		}
+		return m, true
+	}
+	if !t.foreign(n) {
+		cur := n
+		for cur.parent != nil {
+			if cur.parent.right == cur {
+				return cur.parent, true
+			}
+			cur = cur.parent
+		}
+		return nil, false
+	}
+	path := t.path(n.key)
+	for i := len(path) - 2; i >= 0; i-- {
+		if path[i].right == path[i+1] {
+			return path[i], true
+		}
+	}
+	return nil, false
+}
+
+func (n *mapNode[K, V]) rotateRight() {
+	p := n.parent
+	pp := p.parent
+	a, b, c := n.left, n.right, p.right
+	if pp != nil {
+		switch p.dir() {
+		case left:
+			pp.left = n
+		case right:
+			pp.right = n
+		default:
+			panic("bad red-black node")
+		}
+	} else {
+		n.tree.root = n
+	}
+	n.parent, p.parent = pp, n
+	n.left, n.right = a, p
+	p.left, p.right = b, c
+	if a != nil {
+		a.parent = n
+	}
+	if b != nil {
+		b.parent = p
+	}
+	if c != nil {
+		c.parent = p
+	}
+	p.size = 1 + p.left.sizeOf() + p.right.sizeOf()
+	n.size = 1 + n.left.sizeOf() + n.right.sizeOf()
+}
+
+func (n *mapNode[K, V]) rotateLeft() {
+	p := n.parent
+	pp := p.parent
+	a, b, c := p.left, n.left, n.right
+	if pp != nil {
+		switch p.dir() {
+		case left:
+			pp.left = n
+		case right:
+			pp.right = n
+		default:
+			panic("bad red-black node")
+		}
+	} else {
+		n.tree.root = n
+	}
+	n.parent, p.parent = pp, n
+	n.left, n.right = p, c
+	p.left, p.right = a, b
+	if c != nil {
+		c.parent = n
+	}
+	if a != nil {
+		a.parent = p
+	}
+	if b != nil {
+		b.parent = p
+	}
+	p.size = 1 + p.left.sizeOf() + p.right.sizeOf()
+	n.size = 1 + n.left.sizeOf() + n.right.sizeOf()
+}
+
+func (n *mapNode[K, V]) rotate() {
+	switch n.dir() {
+	case right:
+		n.rotateLeft()
+	case left:
+		n.rotateRight()
+	}
+}
+
+func (n *mapNode[K, V]) dir() direction {
+	p := n.parent
+	switch {
+	case p.left == n:
+		return left
+	case p.right == n:
+		return right
+	}
+	panic("bad red-black node")
+}
+
+func (n *mapNode[K, V]) brother() *mapNode[K, V] {
+	p := n.parent
+	switch {
+	case p.left == n:
+		return p.right
+	case p.right == n:
+		return p.left
+	}
+	panic("bad red-black node")
+}
+
+func (n *mapNode[K, V]) ensureInvariants() {
+	p := n.parent
+	if p == nil {
+		n.color = black
+		return
+	}
+	if p.color == black {
+		return
+	}
+	pp := p.parent
+	if pp != nil && pp.color == black {
+		u := p.brother()
+		if u != nil && u.color == red {
+			p.color, pp.color, u.color = black, red, black
+			pp.ensureInvariants()
+		} else {
+			if n.dir() == p.dir() {
+				p.rotate()
+				p.color, pp.color = black, red
+			} else {
+				n.rotate()
+				n.rotate()
+				n.color, pp.color = black, red
+			}
+		}
+	}
+}
+
+func (n *mapNode[K, V]) unlink() (*mapNode[K, V], direction) {
+	var child *mapNode[K, V]
+	if n.left != nil {
+		child = n.left
+	} else {
+		child = n.right
+	}
+	p := n.parent
+	var d direction
+	if p != nil {
+		d = n.dir()
+	}
+	if child != nil {
+		child.parent = p
+	}
+	if p == nil {
+		n.tree.root = child
+	} else if d == left {
+		p.left = child
+	} else {
+		p.right = child
+	}
+	return p, d
+}
+
+// fixDoubleBlack restores the red-black invariants after a black node has
+// been removed from position d of p, leaving that side one black short.
+// A red sibling is rotated above p and recolored, which leaves a black
+// sibling. With a black sibling: a red child on the far side is fixed by
+// rotating the sibling above p and recoloring; a red child on the near
+// side is first rotated above the sibling, which turns it into the far
+// case; with two black children the sibling turns red and the shortage
+// moves up to p, absorbed there if p is red and otherwise fixed from p's
+// parent.
+func (t *Map[K, V]) fixDoubleBlack(p *mapNode[K, V], d direction) {
+	var sib *mapNode[K, V]
+	if d == left {
+		sib = p.right
+	} else {
+		sib = p.left
+	}
+	if sib.color == red {
+		p.color, sib.color = red, black
+		sib.rotate()
+		if d == left {
+			sib = p.right
+		} else {
+			sib = p.left
+		}
+	}
+	var near, far *mapNode[K, V]
+	if d == left {
+		near, far = sib.left, sib.right
+	} else {
+		near, far = sib.right, sib.left
+	}
+	if far != nil && far.color == red {
+		sib.color = p.color
+		p.color, far.color = black, black
+		sib.rotate()
+		return
+	}
+	if near != nil && near.color == red {
+		near.color = black
+		sib.color = red
+		near.rotate()
+		if d == left {
+			sib, far = p.right, p.right.right
+		} else {
+			sib, far = p.left, p.left.left
+		}
+		sib.color = p.color
+		p.color, far.color = black, black
+		sib.rotate()
+		return
+	}
+	sib.color = red
+	if p.color == red {
+		p.color = black
+		return
+	}
+	if p.parent != nil {
+		t.fixDoubleBlack(p.parent, p.dir())
+	}
+}
+
+// Map is a generic red-black tree keyed by K with values of type V. Unlike
+// Tree, it never boxes keys or values into an interface, so inserts and
+// lookups avoid the corresponding allocation and type assertion.
+type Map[K any, V any] struct {
+	root *mapNode[K, V]
+	cmp  func(a, b K) int
+}
+
+// NewFunc creates a new Map ordered by the given comparison function.
+func NewFunc[K any, V any](cmp func(a, b K) int) *Map[K, V] {
+	return &Map[K, V]{cmp: cmp}
+}
+
+// NewOrdered creates a new Map whose keys are ordered by the standard < relation.
+func NewOrdered[K cmp.Ordered, V any]() *Map[K, V] {
+	return NewFunc[K, V](cmp.Compare[K])
+}
+
+// Depth returns the depth of the tree.
+func (t *Map[K, V]) Depth() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.depth()
+}
+
+// Size returns the size of the tree.
+func (t *Map[K, V]) Size() int {
+	return t.root.sizeOf()
+}
+
+// Keys returns the keys of the items in the tree.
+func (t *Map[K, V]) Keys() []K {
+	if t.root == nil {
+		return nil
+	}
+	return t.root.keys()
+}
+
+// Insert inserts a new key-value pair into the tree or replaces the value for an existing key.
+func (t *Map[K, V]) Insert(key K, value V) (V, bool) {
+	if t.root == nil {
+		t.root = &mapNode[K, V]{key: key, value: value, color: black, size: 1, tree: t}
+		var zero V
+		return zero, false
+	}
+	n, dir := t.root.find(key)
+	if t.foreign(n) {
+		panic("rbtree: Insert found a node shared from another Map via With; call Clone first")
+	}
+	switch dir {
+	case exact:
+		oldValue := n.value
+		n.value = value
+		return oldValue, true
+	case left:
+		l := &mapNode[K, V]{key: key, value: value, color: red, size: 1, parent: n, tree: t}
+		n.left = l
+		for anc := n; anc != nil; anc = anc.parent {
+			anc.size++
+		}
+		l.ensureInvariants()
+	case right:
+		l := &mapNode[K, V]{key: key, value: value, color: red, size: 1, parent: n, tree: t}
+		n.right = l
+		for anc := n; anc != nil; anc = anc.parent {
+			anc.size++
+		}
+		l.ensureInvariants()
+	}
+	var zero V
+	return zero, false
+}
+
+// Get returns the value for the given key or the zero value if the key can't be found.
+func (t *Map[K, V]) Get(key K) (V, bool) {
+	if t.root == nil {
+		var zero V
+		return zero, false
+	}
+	n, dir := t.root.find(key)
+	if dir == exact {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes the entry for the given key, if present, and reports
+// whether it was found.
+func (t *Map[K, V]) Delete(key K) (V, bool) {
+	if t.root == nil {
+		var zero V
+		return zero, false
+	}
+	n, dir := t.root.find(key)
+	if dir != exact {
+		var zero V
+		return zero, false
+	}
+	if t.foreign(n) {
+		panic("rbtree: Delete found a node shared from another Map via With; call Clone first")
+	}
+	oldValue := n.value
+	if n.left != nil && n.right != nil {
+		s := n.right
+		for s.left != nil {
+			s = s.left
+		}
+		if t.foreign(s) {
+			panic("rbtree: Delete found a node shared from another Map via With; call Clone first")
+		}
+		n.key, n.value = s.key, s.value
+		n = s
+	}
+	color := n.color
+	child := n.left
+	if child == nil {
+		child = n.right
+	}
+	p, d := n.unlink()
+	for anc := p; anc != nil; anc = anc.parent {
+		anc.size--
+	}
+	if color == black {
+		if child != nil && child.color == red {
+			child.color = black
+		} else if p != nil {
+			t.fixDoubleBlack(p, d)
+		}
+	}
+	return oldValue, true
+}
+
+// Min returns the entry with the smallest key in the tree.
+func (t *Map[K, V]) Min() (K, V, bool) {
+	if t.root == nil {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n := t.root
+	for n.left != nil {
+		n = n.left
+	}
+	return n.key, n.value, true
+}
+
+// Max returns the entry with the largest key in the tree.
+func (t *Map[K, V]) Max() (K, V, bool) {
+	if t.root == nil {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n := t.root
+	for n.right != nil {
+		n = n.right
+	}
+	return n.key, n.value, true
+}
+
+// Floor returns the entry with the largest key less than or equal to key.
+func (t *Map[K, V]) Floor(key K) (K, V, bool) {
+	if t.root == nil {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n, dir := t.root.find(key)
+	if dir == left {
+		if n, ok := t.predecessorOf(n); ok {
+			return n.key, n.value, true
+		}
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	return n.key, n.value, true
+}
+
+// Ceiling returns the entry with the smallest key greater than or equal to key.
+func (t *Map[K, V]) Ceiling(key K) (K, V, bool) {
+	if t.root == nil {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n, dir := t.root.find(key)
+	if dir == right {
+		if n, ok := t.successorOf(n); ok {
+			return n.key, n.value, true
+		}
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	return n.key, n.value, true
+}
+
+// Predecessor returns the entry with the largest key strictly less than key.
+func (t *Map[K, V]) Predecessor(key K) (K, V, bool) {
+	if t.root == nil {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n, dir := t.root.find(key)
+	if dir == right {
+		return n.key, n.value, true
+	}
+	if n, ok := t.predecessorOf(n); ok {
+		return n.key, n.value, true
+	}
+	var zk K
+	var zv V
+	return zk, zv, false
+}
+
+// Successor returns the entry with the smallest key strictly greater than key.
+func (t *Map[K, V]) Successor(key K) (K, V, bool) {
+	if t.root == nil {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n, dir := t.root.find(key)
+	if dir == left {
+		return n.key, n.value, true
+	}
+	if n, ok := t.successorOf(n); ok {
+		return n.key, n.value, true
+	}
+	var zk K
+	var zv V
+	return zk, zv, false
+}
+
+// Select returns the i-th smallest entry in the tree (0-indexed), using the
+// cached subtree sizes to walk down in O(log n).
+func (t *Map[K, V]) Select(i int) (K, V, bool) {
+	if i < 0 || i >= t.root.sizeOf() {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	n := t.root
+	for {
+		ls := n.left.sizeOf()
+		switch {
+		case i < ls:
+			n = n.left
+		case i == ls:
+			return n.key, n.value, true
+		default:
+			i -= ls + 1
+			n = n.right
+		}
+	}
+}
+
+// Rank returns the number of keys in the tree strictly less than key.
+func (t *Map[K, V]) Rank(key K) int {
+	n := t.root
+	rank := 0
+	for n != nil {
+		if t.cmp(key, n.key) <= 0 {
+			n = n.left
+		} else {
+			rank += n.left.sizeOf() + 1
+			n = n.right
+		}
+	}
+	return rank
+}
+
+// String returns the textual representation of the tree.
+func (t *Map[K, V]) String() string {
+	if t.root == nil {
+		return "-"
+	}
+	return t.root.str()
+}
+
+func (n *mapNode[K, V]) str() string {
+	var s string
+	if n.left != nil {
+		s += "(" + n.left.str() + ") "
+	}
+	s += fmt.Sprintf("%v:%v", n.key, n.value)
+	if n.color == black {
+		s += "/B"
+	} else {
+		s += "/R"
+	}
+	if n.right != nil {
+		s += " (" + n.right.str() + ")"
+	}
+	return s
+}
+
+// Check verifies that the keys in the tree are ordered correctly, that
+// every child's parent pointer points back at its parent, and that every
+// cached subtree size is correct. It does not check node colors.
+func (t *Map[K, V]) Check() bool {
+	if t.root == nil {
+		return true
+	}
+	return t.root.check()
+}
+
+// MapIterator is an in-order cursor over a Map, mirroring Iterator.
+type MapIterator[K any, V any] struct {
+	tree    *Map[K, V]
+	node    *mapNode[K, V]
+	lo, hi  K
+	hasLo   bool
+	hasHi   bool
+	started bool
+}
+
+// Iterator returns an iterator positioned before the first entry of the tree.
+func (t *Map[K, V]) Iterator() *MapIterator[K, V] {
+	return &MapIterator[K, V]{tree: t}
+}
+
+// IteratorAt returns an iterator whose current entry is the ceiling of key.
+func (t *Map[K, V]) IteratorAt(key K) *MapIterator[K, V] {
+	it := &MapIterator[K, V]{tree: t}
+	it.Seek(key)
+	return it
+}
+
+// RangeIterator returns an iterator restricted to keys in [lo, hi].
+func (t *Map[K, V]) RangeIterator(lo, hi K) *MapIterator[K, V] {
+	return &MapIterator[K, V]{tree: t, lo: lo, hi: hi, hasLo: true, hasHi: true}
+}
+
+func (it *MapIterator[K, V]) first() *mapNode[K, V] {
+	if it.tree.root == nil {
+		return nil
+	}
+	if !it.hasLo {
+		n := it.tree.root
+		for n.left != nil {
+			n = n.left
+		}
+		return n
+	}
+	n, dir := it.tree.root.find(it.lo)
+	if dir == right {
+		if n, ok := it.tree.successorOf(n); ok {
+			return n
+		}
+		return nil
+	}
+	return n
+}
+
+func (it *MapIterator[K, V]) last() *mapNode[K, V] {
+	if it.tree.root == nil {
+		return nil
+	}
+	if !it.hasHi {
+		n := it.tree.root
+		for n.right != nil {
+			n = n.right
+		}
+		return n
+	}
+	n, dir := it.tree.root.find(it.hi)
+	if dir == left {
+		if n, ok := it.tree.predecessorOf(n); ok {
+			return n
+		}
+		return nil
+	}
+	return n
+}
+
+func (it *MapIterator[K, V]) inRange(n *mapNode[K, V]) bool {
+	if n == nil {
+		return false
+	}
+	if it.hasLo && it.tree.cmp(n.key, it.lo) < 0 {
+		return false
+	}
+	if it.hasHi && it.tree.cmp(n.key, it.hi) > 0 {
+		return false
+	}
+	return true
+}
+
+// Next advances the iterator to the next entry and reports whether one exists.
+func (it *MapIterator[K, V]) Next() bool {
+	if !it.started {
+		it.started = true
+		it.node = it.first()
+	} else if it.node != nil {
+		if n, ok := it.tree.successorOf(it.node); ok {
+			it.node = n
+		} else {
+			it.node = nil
+		}
+	}
+	if !it.inRange(it.node) {
+		it.node = nil
+	}
+	return it.node != nil
+}
+
+// Prev moves the iterator to the previous entry and reports whether one exists.
+func (it *MapIterator[K, V]) Prev() bool {
+	if !it.started {
+		it.started = true
+		it.node = it.last()
+	} else if it.node != nil {
+		if n, ok := it.tree.predecessorOf(it.node); ok {
+			it.node = n
+		} else {
+			it.node = nil
+		}
+	}
+	if !it.inRange(it.node) {
+		it.node = nil
+	}
+	return it.node != nil
+}
+
+// Seek repositions the iterator at the ceiling of key.
+func (it *MapIterator[K, V]) Seek(key K) {
+	it.started = true
+	if it.hasLo && it.tree.cmp(key, it.lo) < 0 {
+		key = it.lo
+	}
+	if it.tree.root == nil {
+		it.node = nil
+		return
+	}
+	n, dir := it.tree.root.find(key)
+	if dir == right {
+		if m, ok := it.tree.successorOf(n); ok {
+			n = m
+		} else {
+			n = nil
+		}
+	}
+	if !it.inRange(n) {
+		n = nil
+	}
+	it.node = n
+}
+
+// Key returns the key at the iterator's current position.
+func (it *MapIterator[K, V]) Key() K {
+	if it.node == nil {
+		var zero K
+		return zero
+	}
+	return it.node.key
+}
+
+// Value returns the value at the iterator's current position.
+func (it *MapIterator[K, V]) Value() V {
+	if it.node == nil {
+		var zero V
+		return zero
+	}
+	return it.node.value
+}
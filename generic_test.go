@@ -0,0 +1,218 @@
+package rbtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func intCmp(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func TestMapInsertGet(t *testing.T) {
+	m := NewFunc[int, string](intCmp)
+	if _, ok := m.Insert(5, "five"); ok {
+		t.Fatalf("Insert on empty tree reported an existing value")
+	}
+	if old, ok := m.Insert(5, "FIVE"); !ok || old != "five" {
+		t.Fatalf("Insert(5, ...) again = (%q, %v), want (five, true)", old, ok)
+	}
+	if v, ok := m.Get(5); !ok || v != "FIVE" {
+		t.Fatalf("Get(5) = (%q, %v), want (FIVE, true)", v, ok)
+	}
+	if _, ok := m.Get(6); ok {
+		t.Fatalf("Get(6) reported a value for a missing key")
+	}
+}
+
+func sameInts(got, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMapDeleteRoot(t *testing.T) {
+	m := NewOrdered[int, int]()
+	m.Insert(1, 1)
+	if v, ok := m.Delete(1); !ok || v != 1 {
+		t.Fatalf("Delete(1) = (%v, %v), want (1, true)", v, ok)
+	}
+	if m.Size() != 0 {
+		t.Fatalf("Size() = %d after deleting the only node, want 0", m.Size())
+	}
+	if _, ok := m.Delete(1); ok {
+		t.Fatalf("Delete on an empty tree reported success")
+	}
+}
+
+func TestMapDeleteNodeWithTwoChildren(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+	if v, ok := m.Delete(10); !ok || v != 10 {
+		t.Fatalf("Delete(10) = (%v, %v), want (10, true)", v, ok)
+	}
+	if !m.Check() {
+		t.Fatalf("Check() failed after deleting a node with two children")
+	}
+	want := []int{3, 5, 7, 12, 15, 20}
+	if got := m.Keys(); !sameInts(got, want) {
+		t.Fatalf("Keys() = %v, want %v", got, want)
+	}
+}
+
+// TestMapDeleteFixupCases runs many random insert/delete sequences, which
+// exercise all four double-black fix-up cases (red sibling; black sibling
+// with two black children; black sibling with a red child near n; black
+// sibling with a red child far from n) without hand-constructing each one,
+// and after every deletion checks key order, parent links and cached sizes
+// via Check().
+func TestMapDeleteFixupCases(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for trial := 0; trial < 200; trial++ {
+		m := NewOrdered[int, int]()
+		ref := map[int]int{}
+		n := 1 + rng.Intn(150)
+		for i := 0; i < n; i++ {
+			k := rng.Intn(60)
+			m.Insert(k, k)
+			ref[k] = k
+			if !m.Check() {
+				t.Fatalf("trial %d: Check() failed after inserting %d", trial, k)
+			}
+		}
+		keys := make([]int, 0, len(ref))
+		for k := range ref {
+			keys = append(keys, k)
+		}
+		rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+		for _, k := range keys {
+			m.Delete(k)
+			delete(ref, k)
+			if !m.Check() {
+				t.Fatalf("trial %d: Check() failed after deleting %d", trial, k)
+			}
+		}
+		if m.Size() != 0 {
+			t.Fatalf("trial %d: Size() = %d after deleting every key, want 0", trial, m.Size())
+		}
+	}
+}
+
+func TestMapNavigation(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k*10)
+	}
+	if k, v, ok := m.Min(); !ok || k != 3 || v != 30 {
+		t.Fatalf("Min() = (%v, %v, %v), want (3, 30, true)", k, v, ok)
+	}
+	if k, v, ok := m.Max(); !ok || k != 20 || v != 200 {
+		t.Fatalf("Max() = (%v, %v, %v), want (20, 200, true)", k, v, ok)
+	}
+	if k, _, ok := m.Floor(9); !ok || k != 7 {
+		t.Fatalf("Floor(9) = (%v, _, %v), want (7, true)", k, ok)
+	}
+	if k, _, ok := m.Floor(7); !ok || k != 7 {
+		t.Fatalf("Floor(7) = (%v, _, %v), want (7, true)", k, ok)
+	}
+	if k, _, ok := m.Ceiling(9); !ok || k != 10 {
+		t.Fatalf("Ceiling(9) = (%v, _, %v), want (10, true)", k, ok)
+	}
+	if k, _, ok := m.Predecessor(5); !ok || k != 3 {
+		t.Fatalf("Predecessor(5) = (%v, _, %v), want (3, true)", k, ok)
+	}
+	if _, _, ok := m.Predecessor(3); ok {
+		t.Fatalf("Predecessor(3) reported a value below the minimum key")
+	}
+	if k, _, ok := m.Successor(15); !ok || k != 20 {
+		t.Fatalf("Successor(15) = (%v, _, %v), want (20, true)", k, ok)
+	}
+	if _, _, ok := m.Successor(20); ok {
+		t.Fatalf("Successor(20) reported a value above the maximum key")
+	}
+}
+
+func TestMapIterator(t *testing.T) {
+	m := NewOrdered[int, int]()
+	want := []int{3, 5, 7, 10, 12, 15, 20}
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+
+	var got []int
+	for it := m.Iterator(); it.Next(); {
+		got = append(got, it.Key())
+	}
+	if !sameInts(got, want) {
+		t.Fatalf("forward Iterator() = %v, want %v", got, want)
+	}
+
+	got = nil
+	for it := m.Iterator(); it.Prev(); {
+		got = append(got, it.Key())
+	}
+	for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+		want[i], want[j] = want[j], want[i]
+	}
+	if !sameInts(got, want) {
+		t.Fatalf("backward Iterator() = %v, want %v", got, want)
+	}
+}
+
+func TestMapIteratorSeekAndRange(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+
+	it := m.IteratorAt(8)
+	if it.Key() != 10 {
+		t.Fatalf("IteratorAt(8).Key() = %v, want 10 (the ceiling of 8)", it.Key())
+	}
+
+	var got []int
+	for rit := m.RangeIterator(5, 15); rit.Next(); {
+		got = append(got, rit.Key())
+	}
+	want := []int{5, 7, 10, 12, 15}
+	if !sameInts(got, want) {
+		t.Fatalf("RangeIterator(5, 15) = %v, want %v", got, want)
+	}
+}
+
+func TestMapSelectAndRank(t *testing.T) {
+	m := NewOrdered[int, int]()
+	keys := []int{10, 5, 15, 3, 7, 12, 20}
+	for _, k := range keys {
+		m.Insert(k, k)
+	}
+	sorted := append([]int(nil), keys...)
+	sort.Ints(sorted)
+	for i, k := range sorted {
+		if sk, _, ok := m.Select(i); !ok || sk != k {
+			t.Fatalf("Select(%d) = (%v, _, %v), want (%v, true)", i, sk, ok, k)
+		}
+		if r := m.Rank(k); r != i {
+			t.Fatalf("Rank(%v) = %d, want %d", k, r, i)
+		}
+	}
+	if _, _, ok := m.Select(len(sorted)); ok {
+		t.Fatalf("Select(%d) reported success past the end of the tree", len(sorted))
+	}
+}
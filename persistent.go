@@ -0,0 +1,105 @@
+package rbtree
+
+// clone returns a deep, fully independent copy of the subtree rooted at n,
+// with parent pointers recomputed for the new nodes so the result is a
+// completely ordinary, mutable tree.
+func (n *mapNode[K, V]) clone(tree *Map[K, V], parent *mapNode[K, V]) *mapNode[K, V] {
+	if n == nil {
+		return nil
+	}
+	c := &mapNode[K, V]{key: n.key, value: n.value, color: n.color, size: n.size, parent: parent, tree: tree}
+	c.left = n.left.clone(tree, c)
+	c.right = n.right.clone(tree, c)
+	return c
+}
+
+// Clone returns a deep copy of the tree. The copy shares no nodes with the
+// receiver, so either tree can be freely mutated afterward.
+func (t *Map[K, V]) Clone() *Map[K, V] {
+	nt := &Map[K, V]{cmp: t.cmp}
+	nt.root = t.root.clone(nt, nil)
+	return nt
+}
+
+// balance applies Okasaki's classical local rebalancing rule for
+// persistent red-black insertion: rather than rotating or recoloring a
+// node in place, it returns a freshly allocated replacement, so a node
+// that is already reachable from another Map is never mutated.
+func balance[K any, V any](tree *Map[K, V], c color, l *mapNode[K, V], key K, value V, r *mapNode[K, V]) *mapNode[K, V] {
+	isRed := func(n *mapNode[K, V]) bool { return n != nil && n.color == red }
+	// mk builds a new node and, for each child that already belongs to
+	// tree (meaning it was itself just built for this same With call, and
+	// so is not yet reachable from anywhere else), points that child's
+	// parent back at the new node. A child still owned by the receiver is
+	// left alone: giving it a parent pointer into tree would mean mutating
+	// a node the receiver (or some other snapshot) still depends on.
+	mk := func(c color, l *mapNode[K, V], key K, value V, r *mapNode[K, V]) *mapNode[K, V] {
+		nn := &mapNode[K, V]{tree: tree, color: c, key: key, value: value, left: l, right: r, size: 1 + l.sizeOf() + r.sizeOf()}
+		if l != nil && l.tree == tree {
+			l.parent = nn
+		}
+		if r != nil && r.tree == tree {
+			r.parent = nn
+		}
+		return nn
+	}
+	if c == black {
+		switch {
+		case isRed(l) && isRed(l.left):
+			return mk(red, mk(black, l.left.left, l.left.key, l.left.value, l.left.right), l.key, l.value, mk(black, l.right, key, value, r))
+		case isRed(l) && isRed(l.right):
+			return mk(red, mk(black, l.left, l.key, l.value, l.right.left), l.right.key, l.right.value, mk(black, l.right.right, key, value, r))
+		case isRed(r) && isRed(r.left):
+			return mk(red, mk(black, l, key, value, r.left.left), r.left.key, r.left.value, mk(black, r.left.right, r.key, r.value, r.right))
+		case isRed(r) && isRed(r.right):
+			return mk(red, mk(black, l, key, value, r.left), r.key, r.value, mk(black, r.right.left, r.right.key, r.right.value, r.right.right))
+		}
+	}
+	return mk(c, l, key, value, r)
+}
+
+// persistInsert inserts key/value into the subtree rooted at n without
+// mutating it, reusing every untouched child subtree by pointer and
+// rebuilding only the O(log n) nodes on the path from the root down to key.
+func persistInsert[K any, V any](tree *Map[K, V], cmp func(a, b K) int, n *mapNode[K, V], key K, value V) *mapNode[K, V] {
+	if n == nil {
+		return &mapNode[K, V]{tree: tree, color: red, key: key, value: value, size: 1}
+	}
+	switch c := cmp(key, n.key); {
+	case c < 0:
+		return balance(tree, n.color, persistInsert(tree, cmp, n.left, key, value), n.key, n.value, n.right)
+	case c > 0:
+		return balance(tree, n.color, n.left, n.key, n.value, persistInsert(tree, cmp, n.right, key, value))
+	default:
+		return &mapNode[K, V]{tree: tree, color: n.color, key: key, value: value, left: n.left, right: n.right, size: n.size}
+	}
+}
+
+// With returns a new Map containing key/value, sharing every untouched
+// subtree with the receiver by pointer. With only allocates new nodes and
+// never writes to a node reachable from the receiver, so the receiver
+// remains valid and unaffected, which makes With safe to call concurrently
+// with reads of the receiver or of any other snapshot derived from it.
+//
+// The reallocated nodes get correct parent pointers (balance wires each
+// new node's freshly built children back to it), so navigation —
+// Iterator, Floor, Ceiling, Predecessor, Successor — walks up from them
+// through parent pointers just as on an ordinary tree. A node still shared
+// with the receiver, on the other hand, keeps whatever parent it had
+// there, which would be wrong from the new tree's point of view; going up
+// from such a node re-descends from the root instead, at O(log n) a step.
+// Insert and Delete panic when the first node they would write to (the
+// matching node or the new node's parent, and for Delete also the
+// in-order successor) is such a shared node. No other node is checked, so
+// the rebalancing after an Insert or Delete that passes the check can
+// still recolor or relink nodes shared with the receiver, corrupting it.
+// Check, which validates parent linkage directly, also
+// reports a shared node as invalid even though the tree is structurally
+// sound. Call Clone first if Check, Insert or Delete are needed.
+func (t *Map[K, V]) With(key K, value V) *Map[K, V] {
+	nt := &Map[K, V]{cmp: t.cmp}
+	root := persistInsert(nt, t.cmp, t.root, key, value)
+	root.color = black
+	nt.root = root
+	return nt
+}
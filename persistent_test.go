@@ -0,0 +1,227 @@
+package rbtree
+
+import "testing"
+
+func TestCloneIsIndependent(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+	c := m.Clone()
+	c.Insert(6, 6)
+	c.Delete(10)
+
+	if _, ok := m.Get(6); ok {
+		t.Fatalf("receiver saw a key inserted into its Clone")
+	}
+	if _, ok := m.Get(10); !ok {
+		t.Fatalf("receiver lost a key deleted from its Clone")
+	}
+	if !c.Check() {
+		t.Fatalf("Clone().Check() failed after ordinary mutation")
+	}
+	if !m.Check() {
+		t.Fatalf("receiver Check() failed after mutating its Clone")
+	}
+}
+
+func TestWithSharesStructureAndLeavesReceiverIntact(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k*10)
+	}
+	m2 := m.With(6, 60)
+
+	if _, ok := m.Get(6); ok {
+		t.Fatalf("receiver saw a key inserted via With")
+	}
+	if v, ok := m2.Get(6); !ok || v != 60 {
+		t.Fatalf("m2.Get(6) = (%v, %v), want (60, true)", v, ok)
+	}
+	if got, want := m2.Size(), m.Size()+1; got != want {
+		t.Fatalf("m2.Size() = %d, want %d", got, want)
+	}
+
+	var got []int
+	for it := m2.Iterator(); it.Next(); {
+		got = append(got, it.Key())
+	}
+	want := []int{3, 5, 6, 7, 10, 12, 15, 20}
+	if !sameInts(got, want) {
+		t.Fatalf("m2.Iterator() = %v, want %v", got, want)
+	}
+}
+
+func TestWithNavigationSeesTheNewKey(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k*10)
+	}
+	m2 := m.With(6, 60)
+
+	if k, v, ok := m2.Successor(5); !ok || k != 6 || v != 60 {
+		t.Fatalf("m2.Successor(5) = (%v, %v, %v), want (6, 60, true)", k, v, ok)
+	}
+	if k, _, ok := m2.Predecessor(7); !ok || k != 6 {
+		t.Fatalf("m2.Predecessor(7) = (%v, _, %v), want (6, true)", k, ok)
+	}
+	if k, v, ok := m2.Floor(6); !ok || k != 6 || v != 60 {
+		t.Fatalf("m2.Floor(6) = (%v, %v, %v), want (6, 60, true)", k, v, ok)
+	}
+	if k, v, ok := m2.Ceiling(6); !ok || k != 6 || v != 60 {
+		t.Fatalf("m2.Ceiling(6) = (%v, %v, %v), want (6, 60, true)", k, v, ok)
+	}
+}
+
+func TestWithDeleteAndInsertRefuseSharedNodes(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+	m2 := m.With(6, 60)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Delete on a node shared via With did not panic")
+			}
+		}()
+		m2.Delete(10)
+	}()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Insert under a shared ancestor did not panic")
+			}
+		}()
+		m2.Insert(11, 110)
+	}()
+
+	// Clone first, as the doc comment on With recommends, and the same
+	// operations should now work without panicking.
+	c := m2.Clone()
+	if _, ok := c.Delete(10); !ok {
+		t.Fatalf("Delete(10) on a Clone of a With result failed")
+	}
+	if !c.Check() {
+		t.Fatalf("Clone().Check() failed after deleting from a cloned With result")
+	}
+}
+
+// TestWithInsertOnExistingSharedKeyRefuses exercises the exact-match branch
+// of Insert specifically: updating the value of a key that already exists
+// and still lives in a subtree shared with the receiver must panic rather
+// than overwrite that node in place, since doing so would silently corrupt
+// the receiver (and any other snapshot sharing the same node).
+func TestWithInsertOnExistingSharedKeyRefuses(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k*10)
+	}
+	w := m.With(1000, 1000)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Insert overwriting a shared, untouched key did not panic")
+			}
+		}()
+		w.Insert(5, 99999)
+	}()
+
+	if v, ok := m.Get(5); !ok || v != 50 {
+		t.Fatalf("m.Get(5) = (%v, %v), want (50, true): receiver was corrupted by w.Insert", v, ok)
+	}
+}
+
+func TestWithCheckRejectsSharedNodes(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+	m2 := m.With(6, 60)
+	if m2.Check() {
+		t.Fatalf("m2.Check() = true, want false: shared nodes carry stale parent pointers")
+	}
+}
+
+func TestWithChainOfSnapshots(t *testing.T) {
+	base := NewOrdered[int, int]()
+	for i := 0; i < 10; i++ {
+		base.Insert(i, i)
+	}
+	snaps := []*Map[int, int]{base}
+	cur := base
+	for i := 10; i < 15; i++ {
+		cur = cur.With(i, i)
+		snaps = append(snaps, cur)
+	}
+	for i, s := range snaps {
+		if want := 10 + i; s.Size() != want {
+			t.Fatalf("snapshot %d: Size() = %d, want %d", i, s.Size(), want)
+		}
+		var prev int
+		first := true
+		for it := s.Iterator(); it.Next(); {
+			if !first && it.Key() <= prev {
+				t.Fatalf("snapshot %d: Iterator() out of order at key %d", i, it.Key())
+			}
+			prev = it.Key()
+			first = false
+		}
+	}
+}
+
+// TestOrdinaryTreeNodesAreNeverForeign guards the performance of
+// successorOf/predecessorOf: they only fall back to the O(log n)
+// root-re-descent when a node is foreign to its tree (shared in from
+// another Map via With). A tree built purely with Insert must never
+// produce a foreign node, or every ordinary traversal would silently
+// regress to paying that cost on each step.
+func TestOrdinaryTreeNodesAreNeverForeign(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20, 1, 4, 6, 8} {
+		m.Insert(k, k)
+	}
+	var walk func(n *mapNode[int, int])
+	walk = func(n *mapNode[int, int]) {
+		if n == nil {
+			return
+		}
+		if m.foreign(n) {
+			t.Fatalf("node with key %v in a plain Insert-only tree reported foreign", n.key)
+		}
+		walk(n.left)
+		walk(n.right)
+	}
+	walk(m.root)
+}
+
+// TestWithRebuiltPathIsNative checks the other half of the same guard: the
+// nodes With rebuilds along the insertion path belong to the new Map (so
+// the O(1) parent-pointer fast path still applies to them), while untouched
+// shared subtrees remain foreign.
+func TestWithRebuiltPathIsNative(t *testing.T) {
+	m := NewOrdered[int, int]()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		m.Insert(k, k)
+	}
+	m2 := m.With(6, 60)
+
+	n, dir := m2.root.find(6)
+	if dir != exact {
+		t.Fatalf("find(6) on m2 did not report an exact match")
+	}
+	if m2.foreign(n) {
+		t.Fatalf("the newly inserted node itself reported foreign on the tree that built it")
+	}
+
+	leaf, dir := m2.root.find(20)
+	if dir != exact {
+		t.Fatalf("find(20) on m2 did not report an exact match")
+	}
+	if !m2.foreign(leaf) {
+		t.Fatalf("an untouched leaf shared from the receiver did not report foreign")
+	}
+}
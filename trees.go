@@ -1,7 +1,5 @@
 package rbtree
 
-import "fmt"
-
 // Comparable is a totally orderable type.
 type Comparable interface {
 	Compare(interface{}) int
@@ -22,317 +20,116 @@ const (
 	right
 )
 
-type node struct {
-	key    Comparable
-	value  interface{}
-	color  color
-	parent *node
-	tree   *Tree
-	left   *node
-	right  *node
-}
+func comparableCompare(a, b Comparable) int { return a.Compare(b) }
 
-func (n *node) check() bool {
-	if n.left != nil {
-		if n.left.key.Compare(n.key) >= 0 {
-			return false
-		}
-		if n.left.parent != n {
-			return false
-		}
-		if !n.left.check() {
-			return false
-		}
-	}
-	if n.right != nil {
-		if n.key.Compare(n.right.key) >= 0 {
-			return false
-		}
-		if n.right.parent != n {
-			return false
-		}
-		if !n.right.check() {
-			return false
-		}
-	}
-	return true
+// Tree is a red-black tree keyed by Comparable values holding interface{}
+// values. It is kept for backward compatibility and is now implemented on
+// top of the generic Map type; callers that don't need interface-boxed
+// keys and values should prefer Map directly to avoid the boxing cost.
+type Tree struct {
+	m *Map[Comparable, interface{}]
 }
 
-func (n *node) depth() int {
-	var ld, rd int
-	if n.left != nil {
-		ld = n.left.depth()
-	}
-	if n.right != nil {
-		rd = n.right.depth()
-	}
-	if ld > rd {
-		return ld + 1
-	} else {
-		return rd + 1
-	}
+// New creates a new red-black tree.
+func New() *Tree {
+	return &Tree{m: NewFunc[Comparable, interface{}](comparableCompare)}
 }
 
-func (n *node) size() int {
-	r := 1
-	if n.left != nil {
-		r += n.left.size()
-	}
-	if n.right != nil {
-		r += n.right.size()
-	}
-	return r
-}
+// Depth returns the depth of the tree.
+func (t *Tree) Depth() int { return t.m.Depth() }
 
-func (n *node) keys() []Comparable {
-	var ks []Comparable
-	if n.left != nil {
-		ks = n.left.keys()
-	}
-	ks = append(ks, n.key)
-	if n.right != nil {
-		ks = append(ks, n.right.keys()...)
-	}
-	return ks
-}
+// Size returns the size of the tree.
+func (t *Tree) Size() int { return t.m.Size() }
 
-func (n *node) str() string {
-	var s string
-	if n.left != nil {
-		s += "(" + n.left.str() + ") "
-	}
-	s += fmt.Sprintf("%v:%v", n.key, n.value)
-	if n.color == black {
-		s += "/B"
-	} else {
-		s += "/R"
-	}
-	if n.right != nil {
-		s += " (" + n.right.str() + ")"
-	}
-	return s
-}
+// Keys returns the keys of the items in the tree.
+func (t *Tree) Keys() []Comparable { return t.m.Keys() }
 
-func (n *node) find(key Comparable) (*node, direction) {
-	c := key.Compare(n.key)
-	switch {
-	case c == 0:
-		return n, exact
-	case c < 0:
-		if n.left == nil {
-			return n, left
-		} else {
-			return n.left.find(key)
-		}
-	case c > 0:
-		if n.right == nil {
-			return n, right
-		} else {
-			return n.right.find(key)
-		}
-	}
-	panic("bad red-black node")
+// Insert inserts a new key-value pair into the tree or replaces the value for an existing key.
+func (t *Tree) Insert(key Comparable, value interface{}) (interface{}, bool) {
+	return t.m.Insert(key, value)
 }
 
-func (n *node) rotateRight() {
-	p := n.parent
-	pp := p.parent
-	a, b, c := n.left, n.right, p.right
-	if pp != nil {
-		switch p.dir() {
-		case left:
-			pp.left = n
-		case right:
-			pp.right = n
-		default:
-			panic("bad red-black node")
-		}
-	} else {
-		n.tree.root = n
-	}
-	n.parent, p.parent = pp, n
-	n.left, n.right = a, p
-	p.left, p.right = b, c
-	if a != nil {
-		a.parent = n
-	}
-	if b != nil {
-		b.parent = p
-	}
-	if c != nil {
-		c.parent = p
-	}
-}
+// Get returns the value for the given key or nil if the key can't be found.
+func (t *Tree) Get(key Comparable) (interface{}, bool) { return t.m.Get(key) }
 
-func (n *node) rotateLeft() {
-	p := n.parent
-	pp := p.parent
-	a, b, c := p.left, n.left, n.right
-	if pp != nil {
-		switch p.dir() {
-		case left:
-			pp.left = n
-		case right:
-			pp.right = n
-		default:
-			panic("bad red-black node")
-		}
-	} else {
-		n.tree.root = n
-	}
-	n.parent, p.parent = pp, n
-	n.left, n.right = p, c
-	p.left, p.right = a, b
-	if c != nil {
-		c.parent = n
-	}
-	if a != nil {
-		a.parent = p
-	}
-	if b != nil {
-		b.parent = p
-	}
-}
+// Delete removes the entry for the given key, if present, and reports
+// whether it was found. The red-black invariants are restored with the
+// standard double-black fix-up after the node (or, for a node with two
+// children, its in-order successor) is spliced out.
+func (t *Tree) Delete(key Comparable) (interface{}, bool) { return t.m.Delete(key) }
 
-func (n *node) rotate() {
-	switch n.dir() {
-	case right:
-		n.rotateLeft()
-	case left:
-		n.rotateRight()
-	}
-}
+// Min returns the entry with the smallest key in the tree.
+func (t *Tree) Min() (Comparable, interface{}, bool) { return t.m.Min() }
 
-func (n *node) dir() direction {
-	p := n.parent
-	switch {
-	case p.left == n:
-		return left
-	case p.right == n:
-		return right
-	}
-	panic("bad red-black node")
-}
+// Max returns the entry with the largest key in the tree.
+func (t *Tree) Max() (Comparable, interface{}, bool) { return t.m.Max() }
 
-func (n *node) brother() *node {
-	p := n.parent
-	switch {
-	case p.left == n:
-		return p.right
-	case p.right == n:
-		return p.left
-	}
-	panic("bad red-black node")
-}
+// Floor returns the entry with the largest key less than or equal to key.
+func (t *Tree) Floor(key Comparable) (Comparable, interface{}, bool) { return t.m.Floor(key) }
 
-func (n *node) ensureInvariants() {
-	p := n.parent
-	if p == nil {
-		n.color = black
-		return
-	}
-	if p.color == black {
-		return
-	}
-	pp := p.parent
-	if pp != nil && pp.color == black {
-		u := p.brother()
-		if u != nil && u.color == red {
-			p.color, pp.color, u.color = black, red, black
-			pp.ensureInvariants()
-		} else {
-			if n.dir() == p.dir() {
-				p.rotate()
-				p.color, pp.color = black, red
-			} else {
-				n.rotate()
-				n.rotate()
-				n.color, pp.color = black, red
-			}
-		}
-	}
-}
+// Ceiling returns the entry with the smallest key greater than or equal to key.
+func (t *Tree) Ceiling(key Comparable) (Comparable, interface{}, bool) { return t.m.Ceiling(key) }
 
-// Tree is a generic red-black tree.
-type Tree struct {
-	root *node
-}
+// Predecessor returns the entry with the largest key strictly less than key.
+func (t *Tree) Predecessor(key Comparable) (Comparable, interface{}, bool) { return t.m.Predecessor(key) }
 
-// New creates a new red-black tree.
-func New() *Tree { return new(Tree) }
+// Successor returns the entry with the smallest key strictly greater than key.
+func (t *Tree) Successor(key Comparable) (Comparable, interface{}, bool) { return t.m.Successor(key) }
 
-// Depth returns the depth of the tree.
-func (t *Tree) Depth() int {
-	if t.root == nil {
-		return 0
-	}
-	return t.root.depth()
-}
+// Clone returns a deep copy of the tree. The copy shares no nodes with the
+// receiver, so either tree can be freely mutated afterward.
+func (t *Tree) Clone() *Tree { return &Tree{m: t.m.Clone()} }
 
-// Size returns the size of the tree.
-func (t *Tree) Size() int {
-	if t.root == nil {
-		return 0
-	}
-	return t.root.size()
-}
+// With returns a new tree containing key/value, sharing untouched
+// structure with the receiver via path-copying rather than a deep copy.
+// See Map.With for the restrictions this places on the result: only
+// Insert, Delete and Check are unsupported on it — call Clone first if
+// those are needed.
+func (t *Tree) With(key Comparable, value interface{}) *Tree { return &Tree{m: t.m.With(key, value)} }
 
-// Keys returns the keys of the items in the tree.
-func (t *Tree) Keys() []Comparable {
-	if t.root == nil {
-		return nil
-	}
-	return t.root.keys()
-}
+// Select returns the i-th smallest entry in the tree (0-indexed).
+func (t *Tree) Select(i int) (Comparable, interface{}, bool) { return t.m.Select(i) }
 
-// Insert inserts a new key-value pair into the tree or replaces the value for an existing key.
-func (t *Tree) Insert(key Comparable, value interface{}) (interface{}, bool) {
-	if t.root == nil {
-		t.root = &node{key: key, value: value, color: black, tree: t}
-		return nil, false
-	}
-	n, dir := t.root.find(key)
-	switch dir {
-	case exact:
-		oldValue := n.value
-		n.value = value
-		return oldValue, true
-	case left:
-		l := &node{key: key, value: value, color: red, parent: n, tree: t}
-		n.left = l
-		l.ensureInvariants()
-	case right:
-		l := &node{key: key, value: value, color: red, parent: n, tree: t}
-		n.right = l
-		l.ensureInvariants()
-	}
-	return nil, false
-}
-
-// Get returns the value for the given key or nil if the key can't be found.
-func (t *Tree) Get(key Comparable) (interface{}, bool) {
-	if t.root == nil {
-		return nil, false
-	}
-	n, dir := t.root.find(key)
-	if dir == exact {
-		return n.value, true
-	}
-	return nil, false
-}
+// Rank returns the number of keys in the tree strictly less than key.
+func (t *Tree) Rank(key Comparable) int { return t.m.Rank(key) }
 
 // String returns the textual representation of the tree.
-func (t *Tree) String() string {
-	if t.root == nil {
-		return "-"
-	}
-	return t.root.str()
-}
+func (t *Tree) String() string { return t.m.String() }
 
 // Check verifies that the keys in the tree are ordered correctly.
-func (t *Tree) Check() bool {
-	if t.root == nil {
-		return true
-	}
-	return t.root.check()
+func (t *Tree) Check() bool { return t.m.Check() }
+
+// Iterator is an in-order cursor over a Tree. It walks the tree using the
+// nodes' parent pointers, so advancing it is O(1) amortized and O(log n)
+// in the worst case, with no auxiliary slice of keys ever materialized.
+// The zero value is not usable; obtain an Iterator from Tree.Iterator,
+// Tree.IteratorAt or Tree.RangeIterator.
+type Iterator struct {
+	it *MapIterator[Comparable, interface{}]
+}
+
+// Iterator returns an iterator positioned before the first entry of the tree.
+func (t *Tree) Iterator() *Iterator { return &Iterator{it: t.m.Iterator()} }
+
+// IteratorAt returns an iterator whose current entry is the ceiling of key.
+func (t *Tree) IteratorAt(key Comparable) *Iterator { return &Iterator{it: t.m.IteratorAt(key)} }
+
+// RangeIterator returns an iterator restricted to keys in [lo, hi].
+func (t *Tree) RangeIterator(lo, hi Comparable) *Iterator {
+	return &Iterator{it: t.m.RangeIterator(lo, hi)}
 }
+
+// Next advances the iterator to the next entry and reports whether one exists.
+func (it *Iterator) Next() bool { return it.it.Next() }
+
+// Prev moves the iterator to the previous entry and reports whether one exists.
+func (it *Iterator) Prev() bool { return it.it.Prev() }
+
+// Seek repositions the iterator at the ceiling of key.
+func (it *Iterator) Seek(key Comparable) { it.it.Seek(key) }
+
+// Key returns the key at the iterator's current position.
+func (it *Iterator) Key() Comparable { return it.it.Key() }
+
+// Value returns the value at the iterator's current position.
+func (it *Iterator) Value() interface{} { return it.it.Value() }
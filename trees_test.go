@@ -0,0 +1,162 @@
+package rbtree
+
+import "testing"
+
+// intKey adapts int to Comparable so Tree, which is keyed by Comparable,
+// can be exercised with plain integers.
+type intKey int
+
+func (k intKey) Compare(other interface{}) int {
+	o := other.(intKey)
+	switch {
+	case k < o:
+		return -1
+	case k > o:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func TestTreeInsertGetDelete(t *testing.T) {
+	tr := New()
+	if _, ok := tr.Insert(intKey(5), "five"); ok {
+		t.Fatalf("Insert on empty tree reported an existing value")
+	}
+	if v, ok := tr.Get(intKey(5)); !ok || v != "five" {
+		t.Fatalf("Get(5) = (%v, %v), want (five, true)", v, ok)
+	}
+	if v, ok := tr.Delete(intKey(5)); !ok || v != "five" {
+		t.Fatalf("Delete(5) = (%v, %v), want (five, true)", v, ok)
+	}
+	if tr.Size() != 0 {
+		t.Fatalf("Size() = %d after deleting the only node, want 0", tr.Size())
+	}
+}
+
+func TestTreeDeleteRootAndTwoChildren(t *testing.T) {
+	tr := New()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		tr.Insert(intKey(k), k)
+	}
+
+	if _, ok := tr.Delete(intKey(10)); !ok {
+		t.Fatalf("Delete(10) (root, two children) reported failure")
+	}
+	if !tr.Check() {
+		t.Fatalf("Check() failed after deleting the root")
+	}
+
+	for _, k := range []int{5, 15, 3, 7, 12, 20} {
+		if _, ok := tr.Get(intKey(k)); !ok {
+			t.Fatalf("Get(%d) missing after deleting the root", k)
+		}
+	}
+}
+
+func TestTreeDeleteEveryFixupCase(t *testing.T) {
+	tr := New()
+	keys := []int{20, 10, 30, 5, 15, 25, 35, 1, 7, 12, 17, 22, 27, 32, 40}
+	for _, k := range keys {
+		tr.Insert(intKey(k), k)
+	}
+	// Deleting in this order reaches every double-black fix-up case.
+	for _, k := range []int{7, 32, 35, 22, 40, 10, 25, 17, 15, 20, 27, 12, 1, 30, 5} {
+		if _, ok := tr.Delete(intKey(k)); !ok {
+			t.Fatalf("Delete(%d) reported failure", k)
+		}
+		if !tr.Check() {
+			t.Fatalf("Check() failed after deleting %d", k)
+		}
+	}
+	if tr.Size() != 0 {
+		t.Fatalf("Size() = %d after deleting every key, want 0", tr.Size())
+	}
+	if _, ok := tr.Delete(intKey(1)); ok {
+		t.Fatalf("Delete on an empty tree reported success")
+	}
+}
+
+func TestTreeNavigation(t *testing.T) {
+	tr := New()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		tr.Insert(intKey(k), k)
+	}
+	if k, _, ok := tr.Min(); !ok || k.(intKey) != 3 {
+		t.Fatalf("Min() = (%v, _, %v), want (3, true)", k, ok)
+	}
+	if k, _, ok := tr.Max(); !ok || k.(intKey) != 20 {
+		t.Fatalf("Max() = (%v, _, %v), want (20, true)", k, ok)
+	}
+	if k, _, ok := tr.Floor(intKey(9)); !ok || k.(intKey) != 7 {
+		t.Fatalf("Floor(9) = (%v, _, %v), want (7, true)", k, ok)
+	}
+	if k, _, ok := tr.Ceiling(intKey(9)); !ok || k.(intKey) != 10 {
+		t.Fatalf("Ceiling(9) = (%v, _, %v), want (10, true)", k, ok)
+	}
+	if k, _, ok := tr.Predecessor(intKey(10)); !ok || k.(intKey) != 7 {
+		t.Fatalf("Predecessor(10) = (%v, _, %v), want (7, true)", k, ok)
+	}
+	if k, _, ok := tr.Successor(intKey(10)); !ok || k.(intKey) != 12 {
+		t.Fatalf("Successor(10) = (%v, _, %v), want (12, true)", k, ok)
+	}
+}
+
+func TestTreeIterator(t *testing.T) {
+	tr := New()
+	for _, k := range []int{10, 5, 15, 3, 7, 12, 20} {
+		tr.Insert(intKey(k), k)
+	}
+	want := []int{3, 5, 7, 10, 12, 15, 20}
+	var got []int
+	for it := tr.Iterator(); it.Next(); {
+		got = append(got, int(it.Key().(intKey)))
+	}
+	if !sameInts(got, want) {
+		t.Fatalf("Iterator() = %v, want %v", got, want)
+	}
+}
+
+func TestTreeSelectAndRank(t *testing.T) {
+	tr := New()
+	keys := []int{10, 5, 15, 3, 7, 12, 20}
+	for _, k := range keys {
+		tr.Insert(intKey(k), k)
+	}
+	sorted := []int{3, 5, 7, 10, 12, 15, 20}
+	for i, k := range sorted {
+		if sk, _, ok := tr.Select(i); !ok || sk.(intKey) != intKey(k) {
+			t.Fatalf("Select(%d) = (%v, _, %v), want (%d, true)", i, sk, ok, k)
+		}
+		if r := tr.Rank(intKey(k)); r != i {
+			t.Fatalf("Rank(%d) = %d, want %d", k, r, i)
+		}
+	}
+}
+
+func TestTreeCloneAndWith(t *testing.T) {
+	tr := New()
+	for _, k := range []int{10, 5, 15} {
+		tr.Insert(intKey(k), k)
+	}
+
+	c := tr.Clone()
+	c.Delete(intKey(10))
+	if _, ok := tr.Get(intKey(10)); !ok {
+		t.Fatalf("receiver lost a key deleted from its Clone")
+	}
+	if !c.Check() {
+		t.Fatalf("Clone().Check() failed")
+	}
+
+	w := tr.With(intKey(6), 60)
+	if _, ok := tr.Get(intKey(6)); ok {
+		t.Fatalf("receiver saw a key inserted via With")
+	}
+	if v, ok := w.Get(intKey(6)); !ok || v != 60 {
+		t.Fatalf("w.Get(6) = (%v, %v), want (60, true)", v, ok)
+	}
+	if k, _, ok := w.Successor(intKey(5)); !ok || k.(intKey) != 6 {
+		t.Fatalf("w.Successor(5) = (%v, _, %v), want (6, true)", k, ok)
+	}
+}